@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+type consumeBlockGasTxHandler struct {
+	inner tx.TxHandler
+}
+
+// NewConsumeBlockGasMiddleware creates a tx.TxMiddleware which meters DeliverTx gas
+// usage against the block gas limit. See NewDefaultTxHandler for how it composes with
+// NewBranchStoreMiddleware.
+func NewConsumeBlockGasMiddleware() tx.TxMiddleware {
+	return func(txh tx.TxHandler) tx.TxHandler {
+		return consumeBlockGasTxHandler{inner: txh}
+	}
+}
+
+var _ tx.TxHandler = consumeBlockGasTxHandler{}
+
+// CheckTx implements TxHandler.CheckTx method.
+func (txh consumeBlockGasTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	return txh.inner.CheckTx(ctx, tx, req)
+}
+
+// DeliverTx implements TxHandler.DeliverTx method.
+func (txh consumeBlockGasTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (res abci.ResponseDeliverTx, err error) {
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+
+	// only run the tx if there is block gas remaining
+	if sdkCtx.BlockGasMeter().IsOutOfGas() {
+		return res, sdkerrors.Wrap(sdkerrors.ErrOutOfGas, "no block gas left to run tx")
+	}
+
+	startingGas := sdkCtx.BlockGasMeter().GasConsumed()
+
+	// If BlockGasMeter() panics it will be caught by the outer panic recovery
+	// middleware and will return an error - in any case BlockGasMeter will consume
+	// gas past the limit.
+	defer func() {
+		sdkCtx.BlockGasMeter().ConsumeGas(
+			sdkCtx.GasMeter().GasConsumedToLimit(), "block gas meter",
+		)
+
+		if sdkCtx.BlockGasMeter().GasConsumed() < startingGas {
+			panic(sdk.ErrorGasOverflow{Descriptor: "tx gas summation"})
+		}
+
+		// The block gas meter only reflects this tx's gas once it has been consumed
+		// above, so the out-of-gas check has to happen here.
+		if err == nil && sdkCtx.BlockGasMeter().IsOutOfGas() {
+			err = sdkerrors.Wrap(sdkerrors.ErrOutOfGas, "block gas limit exceeded while running tx")
+		}
+	}()
+
+	return txh.inner.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements TxHandler.SimulateTx method.
+func (txh consumeBlockGasTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.inner.SimulateTx(ctx, sdkTx, req)
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/cosmos/cosmos-sdk/store/rootmulti"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+type stubTxHandler struct {
+	deliver func(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error)
+}
+
+func (h stubTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	return abci.ResponseCheckTx{}, nil
+}
+
+func (h stubTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	return h.deliver(ctx, sdkTx, req)
+}
+
+func (h stubTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return tx.ResponseSimulateTx{}, nil
+}
+
+// TestConsumeBlockGasMiddleware_RevertsOnBlockGasExceeded submits a tx whose gas usage
+// exceeds the block gas remaining and asserts that neither its state changes nor its
+// events are retained, matching cosmos-sdk#10770.
+func TestConsumeBlockGasMiddleware_RevertsOnBlockGasExceeded(t *testing.T) {
+	storeKey := sdk.NewKVStoreKey("test")
+	ms := rootmulti.NewStore(dbm.NewMemDB())
+	ms.MountStoreWithDB(storeKey, sdk.StoreTypeIAVL, nil)
+	if err := ms.LoadLatestVersion(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The block gas meter starts with exactly as much gas as the tx is about to
+	// consume, so the deferred BlockGasMeter().ConsumeGas call in
+	// consumeBlockGasTxHandler lands it on consumed == limit (IsOutOfGas, no panic)
+	// rather than consumed > limit (which would panic instead of returning an error).
+	sdkCtx := sdk.NewContext(ms, tmproto.Header{}, false, log.NewNopLogger()).
+		WithBlockGasMeter(sdk.NewGasMeter(10)).
+		WithGasMeter(sdk.NewGasMeter(100))
+
+	inner := stubTxHandler{
+		deliver: func(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+			c := sdk.UnwrapSDKContext(ctx)
+			c.KVStore(storeKey).Set([]byte("k"), []byte("v"))
+			c.EventManager().EmitEvent(sdk.NewEvent("test_event"))
+			c.GasMeter().ConsumeGas(10, "all of the remaining block gas")
+
+			return abci.ResponseDeliverTx{}, nil
+		},
+	}
+
+	txh := NewBranchStoreMiddleware()(NewConsumeBlockGasMiddleware()(inner))
+
+	_, err := txh.DeliverTx(sdk.WrapSDKContext(sdkCtx), nil, abci.RequestDeliverTx{})
+	if err == nil {
+		t.Fatal("expected ErrOutOfGas once the tx exceeds the remaining block gas")
+	}
+
+	if sdkCtx.KVStore(storeKey).Has([]byte("k")) {
+		t.Fatal("expected the store write to be reverted")
+	}
+
+	if len(sdkCtx.EventManager().Events()) != 0 {
+		t.Fatal("expected the emitted event to be discarded")
+	}
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+type branchStoreTxHandler struct {
+	inner tx.TxHandler
+}
+
+// NewBranchStoreMiddleware creates a tx.TxMiddleware which runs the inner handler
+// against a branched store and event manager, only committing them back to ctx when
+// the inner handler returns a nil error.
+func NewBranchStoreMiddleware() tx.TxMiddleware {
+	return func(txh tx.TxHandler) tx.TxHandler {
+		return branchStoreTxHandler{inner: txh}
+	}
+}
+
+var _ tx.TxHandler = branchStoreTxHandler{}
+
+// CheckTx implements TxHandler.CheckTx method.
+func (txh branchStoreTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	branchedCtx, commit := branchStore(ctx)
+
+	res, err := txh.inner.CheckTx(branchedCtx, sdkTx, req)
+	if err == nil {
+		commit()
+	}
+
+	return res, err
+}
+
+// DeliverTx implements TxHandler.DeliverTx method.
+func (txh branchStoreTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	branchedCtx, commit := branchStore(ctx)
+
+	res, err := txh.inner.DeliverTx(branchedCtx, sdkTx, req)
+	if err == nil {
+		commit()
+	}
+
+	return res, err
+}
+
+// SimulateTx implements TxHandler.SimulateTx method.
+func (txh branchStoreTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	branchedCtx, commit := branchStore(ctx)
+
+	res, err := txh.inner.SimulateTx(branchedCtx, sdkTx, req)
+	if err == nil {
+		commit()
+	}
+
+	return res, err
+}
+
+// branchStore wraps the sdk.Context in ctx with a cache-wrapped multistore and a fresh
+// EventManager, returning the branched context to pass to inner and a commit func that
+// writes the cache store back and replays the branch's events onto the parent's
+// EventManager. Until commit is called, neither the store writes nor the events are
+// visible outside the branch.
+func branchStore(ctx context.Context) (context.Context, func()) {
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	msCache := sdkCtx.MultiStore().CacheMultiStore()
+	branchedEventManager := sdk.NewEventManager()
+
+	branchedCtx := sdkCtx.WithMultiStore(msCache).WithEventManager(branchedEventManager)
+
+	commit := func() {
+		msCache.Write()
+		sdkCtx.EventManager().EmitEvents(branchedEventManager.Events())
+	}
+
+	return sdk.WrapSDKContext(branchedCtx), commit
+}
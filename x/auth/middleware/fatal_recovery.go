@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"errors"
+	"runtime"
+)
+
+// ErrFatalPanic is a sentinel a keeper can panic with to signal that the panic must
+// never be swallowed into a tx error: recovering from it and continuing would leave
+// validators in a non-deterministic state (e.g. lost sidecar connectivity, a disk I/O
+// error, a corrupted IAVL node). See ADR-022. Use errors.Is against this value, since
+// callers typically wrap it with additional context before panicking.
+var ErrFatalPanic = errors.New("fatal panic: node must halt")
+
+// FatalPanicHandler is invoked by NewFatalRecoveryMiddleware when classify matches; it
+// defaults to re-panicking recoveryObj so it escapes panicTxHandler's defer and
+// crashes the node. Overridable so tests (or an operator wanting a cleaner shutdown)
+// can substitute e.g. an os.Exit call instead of a bare re-panic.
+var FatalPanicHandler = func(recoveryObj interface{}) {
+	panic(recoveryObj)
+}
+
+// NewFatalRecoveryMiddleware creates a recoveryMiddleware that calls FatalPanicHandler
+// instead of returning an error whenever classify(recoveryObj) returns true. Because
+// FatalPanicHandler re-panics by default, the panic propagates past the recover() in
+// panicTxHandler's defer and crashes the process rather than being wrapped into a tx
+// error. Any recoveryObj classify rejects is passed on to next unchanged.
+func NewFatalRecoveryMiddleware(classify func(recoveryObj interface{}) bool, next recoveryMiddleware) recoveryMiddleware {
+	handler := func(recoveryObj interface{}) error {
+		if !classify(recoveryObj) {
+			return nil
+		}
+
+		FatalPanicHandler(recoveryObj)
+		return nil
+	}
+
+	return newRecoveryMiddleware(handler, next)
+}
+
+// DefaultFatalClassifier reports whether recoveryObj is a panic that must crash the
+// node rather than be recovered into a tx error: a runtime.Error (e.g. the kind
+// thrown on memory corruption) or anything matching ErrFatalPanic via errors.Is.
+func DefaultFatalClassifier(recoveryObj interface{}) bool {
+	if _, ok := recoveryObj.(runtime.Error); ok {
+		return true
+	}
+
+	err, ok := recoveryObj.(error)
+	return ok && errors.Is(err, ErrFatalPanic)
+}
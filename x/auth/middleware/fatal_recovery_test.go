@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"errors"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// TestFatalRecoveryMiddleware_ErrFatalPanicEscapes asserts that a panic carrying
+// ErrFatalPanic escapes the recovery chain instead of being turned into an error.
+func TestFatalRecoveryMiddleware_ErrFatalPanicEscapes(t *testing.T) {
+	txh := panicTxHandler{fatalClassify: DefaultFatalClassifier}
+
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatal("expected ErrFatalPanic to escape the recovery chain")
+		}
+		if err, ok := r.(error); !ok || !errors.Is(err, ErrFatalPanic) {
+			t.Fatalf("expected the escaped panic to be ErrFatalPanic, got %v", r)
+		}
+	}()
+
+	_ = processRecovery(ErrFatalPanic, txh.recoveryChain(0, sdk.Context{}, nil))
+	t.Fatal("expected FatalPanicHandler to panic before processRecovery returned")
+}
+
+// TestFatalRecoveryMiddleware_OrdinaryPanicStillWrapped asserts that a panic the fatal
+// classifier rejects is still recovered and wrapped by the default handler.
+func TestFatalRecoveryMiddleware_OrdinaryPanicStillWrapped(t *testing.T) {
+	txh := panicTxHandler{fatalClassify: DefaultFatalClassifier}
+
+	err := processRecovery("ordinary panic", txh.recoveryChain(0, sdk.Context{}, nil))
+	if err == nil {
+		t.Fatal("expected the ordinary panic to be wrapped into an error")
+	}
+}
+
+// TestFatalRecoveryMiddleware_OutOfGasNeverReachesClassifier asserts that
+// sdk.ErrorOutOfGas is still handled by the OutOfGas middleware and never reaches the
+// fatal classifier.
+func TestFatalRecoveryMiddleware_OutOfGasNeverReachesClassifier(t *testing.T) {
+	classifierCalled := false
+	classify := func(recoveryObj interface{}) bool {
+		classifierCalled = true
+		return false
+	}
+
+	txh := panicTxHandler{fatalClassify: classify}
+	sdkCtx := sdk.Context{}.WithGasMeter(sdk.NewInfiniteGasMeter())
+
+	err := processRecovery(sdk.ErrorOutOfGas{Descriptor: "test"}, txh.recoveryChain(1000, sdkCtx, nil))
+	if err == nil {
+		t.Fatal("expected the OutOfGas handler to wrap the panic")
+	}
+	if classifierCalled {
+		t.Fatal("expected the fatal classifier not to be invoked for sdk.ErrorOutOfGas")
+	}
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"fmt"
+	"reflect"
+	"runtime/debug"
+
+	gometrics "github.com/armon/go-metrics"
+	"github.com/tendermint/tendermint/libs/log"
+
+	"github.com/cosmos/cosmos-sdk/telemetry"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// NewLoggingRecoveryMiddleware creates a recoveryMiddleware that observes every
+// recovered panic it is handed without processing it: it logs a structured entry (tx
+// hash, message type URLs, gas wanted/consumed, block height, the recovered value's Go
+// type, and the captured stack trace) to logger, records a tx_panic_total counter
+// labeled by message type and panic kind (oog vs other) plus a gas-at-panic histogram
+// on metrics, and then always passes the recoveryObj on to next so the handlers after
+// it still produce the final wrapped error.
+// PanicRecoveryMiddleware.WithLoggingRecoveryMiddleware inserts it between
+// newOutOfGasRecoveryMiddleware and newDefaultRecoveryMiddleware; in that position an
+// sdk.ErrorOutOfGas panic is handled by the OutOfGas handler ahead of it and does not
+// reach it.
+func NewLoggingRecoveryMiddleware(logger log.Logger, metrics *telemetry.Metrics, sdkCtx sdk.Context, sdkTx sdk.Tx, next recoveryMiddleware) recoveryMiddleware {
+	handler := func(recoveryObj interface{}) error {
+		gasWanted := sdkCtx.GasMeter().Limit()
+		gasUsed := sdkCtx.GasMeter().GasConsumed()
+
+		_, isOutOfGas := recoveryObj.(sdk.ErrorOutOfGas)
+		kind := "other"
+		if isOutOfGas {
+			kind = "oog"
+		}
+
+		msgTypeURLs := msgTypeURLs(sdkTx)
+
+		logger.Error(
+			"panic recovered in tx execution",
+			"height", sdkCtx.BlockHeight(),
+			"tx_hash", fmt.Sprintf("%X", sdkCtx.TxBytes()),
+			"msg_types", msgTypeURLs,
+			"gas_wanted", gasWanted,
+			"gas_used", gasUsed,
+			"panic_type", reflect.TypeOf(recoveryObj).String(),
+			"stack", string(debug.Stack()),
+		)
+
+		if metrics != nil {
+			labels := []gometrics.Label{
+				{Name: "kind", Value: kind},
+			}
+			for _, msgTypeURL := range msgTypeURLs {
+				labels = append(labels, gometrics.Label{Name: "msg_type", Value: msgTypeURL})
+			}
+
+			metrics.IncrCounterWithLabels([]string{"tx", "panic", "total"}, 1, labels)
+			metrics.AddSampleWithLabels([]string{"tx", "panic", "gas"}, float32(gasUsed), labels)
+		}
+
+		// Always return nil: this middleware only observes, the handlers further down
+		// the chain (the fatal classifier, if enabled, and the default handler) still
+		// wrap recoveryObj into the response error returned to Tendermint.
+		return nil
+	}
+
+	return newRecoveryMiddleware(handler, next)
+}
+
+// msgTypeURLs returns the Any type URL of each sdk.Msg carried by tx, for logging and
+// telemetry labels. It returns nil when tx is nil.
+func msgTypeURLs(tx sdk.Tx) []string {
+	if tx == nil {
+		return nil
+	}
+
+	msgs := tx.GetMsgs()
+	urls := make([]string, len(msgs))
+	for i, msg := range msgs {
+		urls[i] = sdk.MsgTypeURL(msg)
+	}
+
+	return urls
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tendermint/tendermint/libs/log"
+
+	"github.com/cosmos/cosmos-sdk/telemetry"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+type capturingLogger struct {
+	log.Logger
+	errorMsgs []string
+}
+
+func (l *capturingLogger) Error(msg string, keyvals ...interface{}) {
+	l.errorMsgs = append(l.errorMsgs, msg)
+}
+
+// TestLoggingRecoveryMiddleware_AlwaysContinuesChain asserts the logging handler never
+// stops the recovery chain itself: it must return nil so that the handlers further
+// down still produce the response error.
+func TestLoggingRecoveryMiddleware_AlwaysContinuesChain(t *testing.T) {
+	logger := &capturingLogger{}
+	sdkCtx := sdk.Context{}.WithGasMeter(sdk.NewInfiniteGasMeter())
+
+	wantErr := newDefaultRecoveryMiddleware()
+	mw := NewLoggingRecoveryMiddleware(logger, nil, sdkCtx, nil, wantErr)
+
+	next, err := mw("some panic")
+	if err != nil {
+		t.Fatalf("expected the logging middleware to return a nil error, got %v", err)
+	}
+	if next == nil {
+		t.Fatal("expected the logging middleware to pass recoveryObj on to next")
+	}
+}
+
+// TestLoggingRecoveryMiddleware_LogsAndRecordsTelemetry asserts a recovered panic
+// produces both a structured log entry and a tx_panic_total counter.
+func TestLoggingRecoveryMiddleware_LogsAndRecordsTelemetry(t *testing.T) {
+	logger := &capturingLogger{}
+	sdkCtx := sdk.Context{}.WithGasMeter(sdk.NewInfiniteGasMeter())
+
+	metrics, err := telemetry.New(telemetry.Config{Enabled: true, ServiceName: "test"})
+	if err != nil {
+		t.Fatalf("failed to build test telemetry.Metrics: %v", err)
+	}
+
+	mw := NewLoggingRecoveryMiddleware(logger, metrics, sdkCtx, nil, newDefaultRecoveryMiddleware())
+	if _, err := mw("some panic"); err != nil {
+		t.Fatalf("expected a nil error, got %v", err)
+	}
+
+	if len(logger.errorMsgs) != 1 {
+		t.Fatalf("expected exactly one log entry, got %d", len(logger.errorMsgs))
+	}
+
+	gr, err := metrics.Gather(telemetry.FormatText)
+	if err != nil {
+		t.Fatalf("failed to gather telemetry: %v", err)
+	}
+	if !strings.Contains(string(gr.Metrics), "tx_panic_total") {
+		t.Fatalf("expected tx_panic_total to be recorded, got:\n%s", gr.Metrics)
+	}
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import "github.com/cosmos/cosmos-sdk/types/tx"
+
+// NewDefaultTxHandler composes the default tx.TxHandler stack around inner: panic
+// recovery outermost, then branch-store, then block-gas consumption. This ordering
+// matters: branch-store must sit between recovery and block-gas so that a
+// block-gas-exceeded error (or a panic recovered above it) discards the tx's state
+// writes and events rather than committing them. panicMW is optional; pass nil to use
+// NewPanicTxMiddleware() with no custom handlers.
+func NewDefaultTxHandler(inner tx.TxHandler, panicMW *PanicRecoveryMiddleware) tx.TxHandler {
+	if panicMW == nil {
+		panicMW = NewPanicTxMiddleware()
+	}
+
+	txh := NewConsumeBlockGasMiddleware()(inner)
+	txh = NewBranchStoreMiddleware()(txh)
+	txh = panicMW.Middleware(txh)
+
+	return txh
+}
@@ -6,24 +6,114 @@ import (
 	"runtime/debug"
 
 	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
 
+	"github.com/cosmos/cosmos-sdk/telemetry"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
 	"github.com/cosmos/cosmos-sdk/types/tx"
 )
 
 type panicTxHandler struct {
-	inner tx.TxHandler
+	inner          tx.TxHandler
+	userHandlers   []RecoveryHandler
+	loggingLogger  log.Logger
+	loggingMetrics *telemetry.Metrics
+	fatalClassify  func(recoveryObj interface{}) bool
 }
 
-func NewPanicTxMiddleware() tx.TxMiddleware {
-	return func(txh tx.TxHandler) tx.TxHandler {
-		return panicTxHandler{inner: txh}
+// PanicRecoveryMiddleware builds the panicTxHandler wrapping around inner, chaining
+// any user-supplied RecoveryHandlers in front of the built-in OutOfGas and default
+// handlers. Use NewPanicTxMiddleware to construct one.
+type PanicRecoveryMiddleware struct {
+	userHandlers   []RecoveryHandler
+	loggingLogger  log.Logger
+	loggingMetrics *telemetry.Metrics
+	fatalClassify  func(recoveryObj interface{}) bool
+}
+
+// NewPanicTxMiddleware creates a PanicRecoveryMiddleware whose Middleware method is a
+// tx.TxMiddleware which recovers panics emitted in the inner handler chain. userHandlers, if any, are prepended (in the order given) to the
+// built-in newOutOfGasRecoveryMiddleware -> newDefaultRecoveryMiddleware chain, so that
+// CheckTx/DeliverTx/SimulateTx try user1 -> user2 -> ... -> outOfGas -> default. This
+// lets app authors escalate specific panics (e.g. promote a keeper panic into a node
+// crash, or ship panics to an external sink) instead of always swallowing them into a
+// wrapped sdkerrors.ErrPanic. See ADR-022 for the motivating design.
+//
+// This middleware is meant to sit outermost in the stack, ahead of
+// NewBranchStoreMiddleware and NewConsumeBlockGasMiddleware, so that a panic anywhere
+// in the inner chain still unwinds through the block-gas defer, and past the
+// branch-store commit, before being turned into a response.
+func NewPanicTxMiddleware(userHandlers ...RecoveryHandler) *PanicRecoveryMiddleware {
+	return &PanicRecoveryMiddleware{userHandlers: userHandlers}
+}
+
+// AddRunTxRecoveryHandler appends one or more RecoveryHandlers to the front of the
+// built-in recovery chain. It must be called before Middleware is wired into the
+// tx handler stack; it does not affect panicTxHandlers already constructed.
+func (m *PanicRecoveryMiddleware) AddRunTxRecoveryHandler(handlers ...RecoveryHandler) {
+	m.userHandlers = append(m.userHandlers, handlers...)
+}
+
+// WithLoggingRecoveryMiddleware enables NewLoggingRecoveryMiddleware on the recovery
+// chain, inserted between the built-in OutOfGas and default handlers. Operators
+// concerned about ADR-022 determinism can use this to observe recovered panics
+// (structured log + telemetry) without changing which handler ultimately produces the
+// response error. Panics that a user handler or the OutOfGas handler has already
+// processed do not reach it. Returns m to allow chaining off NewPanicTxMiddleware.
+func (m *PanicRecoveryMiddleware) WithLoggingRecoveryMiddleware(logger log.Logger, metrics *telemetry.Metrics) *PanicRecoveryMiddleware {
+	m.loggingLogger = logger
+	m.loggingMetrics = metrics
+
+	return m
+}
+
+// WithFatalClassifier enables NewFatalRecoveryMiddleware on the recovery chain, using
+// classify to decide which panics must crash the node (per ADR-022) instead of being
+// recovered into a tx error. It runs after the built-in OutOfGas handler, so a genuine
+// sdk.ErrorOutOfGas panic is still absorbed there and never reaches classify. Returns
+// m to allow chaining off NewPanicTxMiddleware.
+func (m *PanicRecoveryMiddleware) WithFatalClassifier(classify func(recoveryObj interface{}) bool) *PanicRecoveryMiddleware {
+	m.fatalClassify = classify
+
+	return m
+}
+
+// Middleware implements tx.TxMiddleware.
+func (m *PanicRecoveryMiddleware) Middleware(txh tx.TxHandler) tx.TxHandler {
+	return panicTxHandler{
+		inner:          txh,
+		userHandlers:   m.userHandlers,
+		loggingLogger:  m.loggingLogger,
+		loggingMetrics: m.loggingMetrics,
+		fatalClassify:  m.fatalClassify,
 	}
 }
 
 var _ tx.TxHandler = panicTxHandler{}
 
+// recoveryChain builds the recoveryMiddleware chain for this handler: the configured
+// userHandlers, in order, followed by the built-in OutOfGas handler, the optional
+// logging middleware, the optional fatal classifier, and finally the default handler.
+// Logging sits ahead of the fatal classifier so that the catastrophic panics it
+// classifies are still logged before the node crashes.
+func (txh panicTxHandler) recoveryChain(gasWanted uint64, sdkCtx sdk.Context, sdkTx sdk.Tx) recoveryMiddleware {
+	base := newDefaultRecoveryMiddleware()
+	if txh.fatalClassify != nil {
+		base = NewFatalRecoveryMiddleware(txh.fatalClassify, base)
+	}
+	if txh.loggingLogger != nil {
+		base = NewLoggingRecoveryMiddleware(txh.loggingLogger, txh.loggingMetrics, sdkCtx, sdkTx, base)
+	}
+
+	chain := newOutOfGasRecoveryMiddleware(gasWanted, sdkCtx, base)
+	for i := len(txh.userHandlers) - 1; i >= 0; i-- {
+		chain = newRecoveryMiddleware(txh.userHandlers[i], chain)
+	}
+
+	return chain
+}
+
 // CheckTx implements TxHandler.CheckTx method.
 func (txh panicTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (res abci.ResponseCheckTx, err error) {
 	sdkCtx := sdk.UnwrapSDKContext(ctx)
@@ -33,8 +123,7 @@ func (txh panicTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.Reque
 		gasWanted := sdkCtx.GasMeter().Limit()
 
 		if r := recover(); r != nil {
-			recoveryMW := newOutOfGasRecoveryMiddleware(gasWanted, sdkCtx, newDefaultRecoveryMiddleware())
-			err = processRecovery(r, recoveryMW)
+			err = processRecovery(r, txh.recoveryChain(gasWanted, sdkCtx, tx))
 		}
 	}()
 
@@ -42,39 +131,21 @@ func (txh panicTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.Reque
 }
 
 // DeliverTx implements TxHandler.DeliverTx method.
+//
+// Block-gas metering used to live here too; it is now handled by
+// NewConsumeBlockGasMiddleware, which NewDefaultTxHandler places inside this middleware
+// and inside NewBranchStoreMiddleware so that a
+// block-gas-exceeded tx reverts its state writes instead of having them committed
+// alongside the returned error.
 func (txh panicTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, req abci.RequestDeliverTx) (res abci.ResponseDeliverTx, err error) {
 	sdkCtx := sdk.UnwrapSDKContext(ctx)
-	// only run the tx if there is block gas remaining
-	if sdkCtx.BlockGasMeter().IsOutOfGas() {
-		err = sdkerrors.Wrap(sdkerrors.ErrOutOfGas, "no block gas left to run tx")
-		return
-	}
-
-	startingGas := sdkCtx.BlockGasMeter().GasConsumed()
-
 	// Panic recovery.
 	defer func() {
 		// GasMeter expected to be set in AnteHandler
 		gasWanted := sdkCtx.GasMeter().Limit()
 
 		if r := recover(); r != nil {
-			recoveryMW := newOutOfGasRecoveryMiddleware(gasWanted, sdkCtx, newDefaultRecoveryMiddleware())
-			err = processRecovery(r, recoveryMW)
-		}
-	}()
-
-	// If BlockGasMeter() panics it will be caught by the above recover and will
-	// return an error - in any case BlockGasMeter will consume gas past the limit.
-	//
-	// NOTE: This must exist in a separate defer function for the above recovery
-	// to recover from this one.
-	defer func() {
-		sdkCtx.BlockGasMeter().ConsumeGas(
-			sdkCtx.GasMeter().GasConsumedToLimit(), "block gas meter",
-		)
-
-		if sdkCtx.BlockGasMeter().GasConsumed() < startingGas {
-			panic(sdk.ErrorGasOverflow{Descriptor: "tx gas summation"})
+			err = processRecovery(r, txh.recoveryChain(gasWanted, sdkCtx, tx))
 		}
 	}()
 
@@ -90,18 +161,17 @@ func (txh panicTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.R
 		gasWanted := sdkCtx.GasMeter().Limit()
 
 		if r := recover(); r != nil {
-			recoveryMW := newOutOfGasRecoveryMiddleware(gasWanted, sdkCtx, newDefaultRecoveryMiddleware())
-			err = processRecovery(r, recoveryMW)
+			err = processRecovery(r, txh.recoveryChain(gasWanted, sdkCtx, sdkTx))
 		}
 	}()
 
 	return txh.inner.SimulateTx(ctx, sdkTx, req)
 }
 
-// RecoveryHandler handles recovery() object.
+// RecoveryHandler handles a recover() object.
 // Return a non-nil error if recoveryObj was processed.
 // Return nil if recoveryObj was not processed.
-type recoveryHandler func(recoveryObj interface{}) error
+type RecoveryHandler func(recoveryObj interface{}) error
 
 // recoveryMiddleware is wrapper for RecoveryHandler to create chained recovery handling.
 // returns (recoveryMiddleware, nil) if recoveryObj was not processed and should be passed to the next middleware in chain.
@@ -124,7 +194,7 @@ func processRecovery(recoveryObj interface{}, middleware recoveryMiddleware) err
 }
 
 // newRecoveryMiddleware creates a RecoveryHandler middleware.
-func newRecoveryMiddleware(handler recoveryHandler, next recoveryMiddleware) recoveryMiddleware {
+func newRecoveryMiddleware(handler RecoveryHandler, next recoveryMiddleware) recoveryMiddleware {
 	return func(recoveryObj interface{}) (recoveryMiddleware, error) {
 		if err := handler(recoveryObj); err != nil {
 			return nil, err
@@ -164,4 +234,4 @@ func newDefaultRecoveryMiddleware() recoveryMiddleware {
 	}
 
 	return newRecoveryMiddleware(handler, nil)
-}
\ No newline at end of file
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"errors"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+type testDistinguishablePanic struct {
+	msg string
+}
+
+// TestPanicTxHandler_CustomRecoveryHandlerIntercepts shows a custom RecoveryHandler
+// intercepting a distinguishable panic type before the default handler gets a chance
+// to wrap it in sdkerrors.ErrPanic.
+func TestPanicTxHandler_CustomRecoveryHandlerIntercepts(t *testing.T) {
+	wantErr := errors.New("handled by custom handler")
+	custom := func(recoveryObj interface{}) error {
+		if _, ok := recoveryObj.(testDistinguishablePanic); ok {
+			return wantErr
+		}
+		return nil
+	}
+
+	txh := panicTxHandler{userHandlers: []RecoveryHandler{custom}}
+
+	err := processRecovery(testDistinguishablePanic{msg: "boom"}, txh.recoveryChain(0, sdk.Context{}, nil))
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("expected custom handler to intercept the panic, got %v", err)
+	}
+}
+
+// TestPanicTxHandler_CustomRecoveryHandlerPassesThrough checks an unrelated panic
+// still falls through to the default handler when the custom handler declines it.
+func TestPanicTxHandler_CustomRecoveryHandlerPassesThrough(t *testing.T) {
+	custom := func(recoveryObj interface{}) error {
+		if _, ok := recoveryObj.(testDistinguishablePanic); ok {
+			return errors.New("should not match")
+		}
+		return nil
+	}
+
+	txh := panicTxHandler{userHandlers: []RecoveryHandler{custom}}
+
+	err := processRecovery("some other panic", txh.recoveryChain(0, sdk.Context{}, nil))
+	if err == nil {
+		t.Fatal("expected the default handler to wrap the unrelated panic")
+	}
+}